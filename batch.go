@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// defaultBatchSize is the number of rows streamed per COPY round-trip when
+// the Store was not configured with WithBatchSize.
+const defaultBatchSize = 1000
+
+// BatchAdd persists values using PostgreSQL's COPY protocol, streaming them
+// in batchSize-sized round-trips rather than one prepared INSERT per item,
+// and returns one freshly generated UUIDv4 key per value, in the same order
+// as values.
+// Err is non-nil in case of failure, in which case no value was persisted.
+func (s Store) BatchAdd(ctx context.Context, values []json.Marshaler) ([]string, error) {
+	keys := make([]string, len(values))
+	for i := range keys {
+		keys[i] = uuid.New().String()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.copyIn(ctx, tx, s.tablename, keys, values); err != nil {
+		return nil, err
+	}
+
+	return keys, tx.Commit()
+}
+
+// BatchSet upserts entries using PostgreSQL's COPY protocol. Because COPY
+// does not support ON CONFLICT, entries are first streamed into a temporary
+// table that only exists for the lifetime of the transaction, then merged
+// into the table with a single INSERT ... ON CONFLICT (k) DO UPDATE so that
+// upsert semantics match Set.
+// Err is non-nil in case of failure, in which case no entry was persisted.
+func (s Store) BatchSet(ctx context.Context, entries map[string]json.Marshaler) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tmp := "tmp_" + s.tablename
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE "`+tmp+`" (k TEXT NOT NULL, v jsonb NOT NULL) ON COMMIT DROP`); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(entries))
+	values := make([]json.Marshaler, 0, len(entries))
+	for k, v := range entries {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	if err := s.copyIn(ctx, tx, tmp, keys, values); err != nil {
+		return err
+	}
+
+	setOnConflict := `SET v=EXCLUDED.v`
+	if s.ttlEnabled {
+		// Matches Set: clear any expiry left over from a previous
+		// SetWithTTL, or the fresh value would stay invisible until the
+		// stale expires_at is overwritten.
+		setOnConflict = `SET v=EXCLUDED.v, expires_at=NULL`
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO "`+s.tablename+`" (k, v) SELECT k, v FROM "`+tmp+`" ON CONFLICT (k) DO UPDATE `+setOnConflict,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// copyIn streams keys and values into table using pq.CopyIn, in chunks of at
+// most s.batchSize rows per round-trip.
+func (s Store) copyIn(ctx context.Context, tx *sql.Tx, table string, keys []string, values []json.Marshaler) error {
+	batchSize := s.batchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := s.copyInBatch(ctx, tx, table, keys[start:end], values[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s Store) copyInBatch(ctx context.Context, tx *sql.Tx, table string, keys []string, values []json.Marshaler) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, "k", "v"))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, k := range keys {
+		b, err := values[i].MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, k, b); err != nil {
+			return err
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	return stmt.Close()
+}
@@ -0,0 +1,181 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gokv/postgres"
+)
+
+func TestBatchAdd(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_batch_add", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_batch_add"); err != nil {
+		panic(err)
+	}
+
+	values := []String{"value0", "value1", "value2"}
+	marshalers := make([]json.Marshaler, len(values))
+	for i := range values {
+		marshalers[i] = values[i]
+	}
+
+	keys, err := s.BatchAdd(context.Background(), marshalers)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if have, want := len(keys), len(values); have != want {
+		t.Fatalf("expected %d keys, found %d", want, have)
+	}
+
+	for i, k := range keys {
+		var got String
+		ok, err := s.Get(context.Background(), k, &got)
+		if err != nil {
+			t.Errorf("getting %q: %v", k, err)
+		}
+		if !ok {
+			t.Errorf("expected key %q to be found", k)
+		}
+		if got != values[i] {
+			t.Errorf("item %d: expected %q, found %q", i, values[i], got)
+		}
+	}
+}
+
+func TestBatchSet(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_batch_set", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_batch_set"); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO test_batch_set (k, v) VALUES ($1, $2)", "key0", `"pre-existing value"`); err != nil {
+		panic(err)
+	}
+
+	entries := map[string]String{
+		"key0": "new value",
+		"key1": "value1",
+	}
+	marshalers := make(map[string]json.Marshaler, len(entries))
+	for k, v := range entries {
+		marshalers[k] = v
+	}
+
+	if err := s.BatchSet(context.Background(), marshalers); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for k, want := range entries {
+		var got String
+		ok, err := s.Get(context.Background(), k, &got)
+		if err != nil {
+			t.Errorf("getting %q: %v", k, err)
+		}
+		if !ok {
+			t.Errorf("expected key %q to be found", k)
+		}
+		if got != want {
+			t.Errorf("key %q: expected %q, found %q", k, want, got)
+		}
+	}
+}
+
+func TestBatchSetClearsExpiry(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_batch_set_ttl", postgres.WithCreateTable, postgres.WithTTLColumn, postgres.WithSweepInterval(time.Hour))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_batch_set_ttl"); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	if err := s.SetWithTTL(ctx, "key", String("old value"), 50*time.Millisecond); err != nil {
+		panic(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if err := s.BatchSet(ctx, map[string]json.Marshaler{"key": String("fresh value")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v String
+	ok, err := s.Get(ctx, "key", &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected BatchSet to clear the stale expiry and make the row visible again")
+	}
+	if have, want := v, String("fresh value"); have != want {
+		t.Errorf("expected %q, found %q", want, have)
+	}
+}
+
+func BenchmarkBatchAdd(b *testing.B) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "bench_batch_add", postgres.WithCreateTable, postgres.WithBatchSize(500))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	values := make([]json.Marshaler, 1000)
+	for i := range values {
+		values[i] = String(fmt.Sprint("value", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.BatchAdd(context.Background(), values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBatchSet(b *testing.B) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "bench_batch_set", postgres.WithCreateTable, postgres.WithBatchSize(500))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	entries := make(map[string]json.Marshaler, 1000)
+	for i := 0; i < 1000; i++ {
+		entries[fmt.Sprint("key", i)] = String(fmt.Sprint("value", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.BatchSet(context.Background(), entries); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/gokv/store"
+)
+
+// CompareAndSwap replaces the value stored at k with new, but only if its
+// current value equals old as jsonb. It reports whether the swap happened;
+// a false return with a nil error means the current value had already
+// diverged from old, or that k does not exist (or has expired).
+// Err is non-nil in case of failure.
+func (s Store) CompareAndSwap(ctx context.Context, k string, old, new json.Marshaler) (bool, error) {
+	oldB, err := old.MarshalJSON()
+	if err != nil {
+		return false, err
+	}
+
+	newB, err := new.MarshalJSON()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.casStmt.ExecContext(ctx, k, oldB, newB)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// UpdateFunc reads the current value at k, locking the row for the
+// duration of the transaction with SELECT ... FOR UPDATE, applies fn to it,
+// and writes the result back atomically. It returns store.ErrNoRows if k
+// does not exist.
+// Err is non-nil in case of failure, in which case no change was persisted.
+func (s Store) UpdateFunc(ctx context.Context, k string, fn func(current []byte) ([]byte, error)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	notExpired := ""
+	if s.ttlEnabled {
+		notExpired = ` AND (expires_at IS NULL OR expires_at > now())`
+	}
+
+	var current []byte
+	err = tx.QueryRowContext(ctx, `SELECT v FROM "`+s.tablename+`" WHERE k=$1`+notExpired+` FOR UPDATE`, k).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrNoRows
+		}
+		return err
+	}
+
+	next, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE "`+s.tablename+`" SET v=$2 WHERE k=$1`, k, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,153 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gokv/postgres"
+	"github.com/gokv/store"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_cas", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_cas"); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO test_cas (k, v) VALUES ($1, $2)", "key", `"old value"`); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	t.Run("swaps when old matches", func(t *testing.T) {
+		swapped, err := s.CompareAndSwap(ctx, "key", String("old value"), String("new value"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !swapped {
+			t.Error("expected swap to happen")
+		}
+
+		var v String
+		if _, err := s.Get(ctx, "key", &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if have, want := v, String("new value"); have != want {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+	})
+
+	t.Run("does not swap when old no longer matches", func(t *testing.T) {
+		swapped, err := s.CompareAndSwap(ctx, "key", String("stale value"), String("other value"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if swapped {
+			t.Error("expected swap not to happen")
+		}
+
+		var v String
+		if _, err := s.Get(ctx, "key", &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if have, want := v, String("new value"); have != want {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+	})
+}
+
+func TestUpdateFunc(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_update_func", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_update_func"); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO test_update_func (k, v) VALUES ($1, $2)", "key", `"1"`); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	t.Run("applies fn to the current value", func(t *testing.T) {
+		err := s.UpdateFunc(ctx, "key", func(current []byte) ([]byte, error) {
+			return []byte(`"2"`), nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var v String
+		if _, err := s.Get(ctx, "key", &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if have, want := v, String("2"); have != want {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+	})
+
+	t.Run("returns ErrNoRows if the key does not exist", func(t *testing.T) {
+		err := s.UpdateFunc(ctx, "missing", func(current []byte) ([]byte, error) {
+			return current, nil
+		})
+		if err != store.ErrNoRows {
+			t.Errorf("expected %v, found %v", store.ErrNoRows, err)
+		}
+	})
+}
+
+func TestCompareAndSwapAndUpdateFuncIgnoreExpiredRows(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_cas_ttl", postgres.WithCreateTable, postgres.WithTTLColumn, postgres.WithSweepInterval(time.Hour))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_cas_ttl"); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	if err := s.SetWithTTL(ctx, "key", String("old value"), 50*time.Millisecond); err != nil {
+		panic(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	t.Run("CompareAndSwap does not resurrect an expired row", func(t *testing.T) {
+		swapped, err := s.CompareAndSwap(ctx, "key", String("old value"), String("new value"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if swapped {
+			t.Error("expected swap not to happen against an expired row")
+		}
+	})
+
+	t.Run("UpdateFunc treats an expired row as not found", func(t *testing.T) {
+		err := s.UpdateFunc(ctx, "key", func(current []byte) ([]byte, error) {
+			return current, nil
+		})
+		if err != store.ErrNoRows {
+			t.Errorf("expected %v, found %v", store.ErrNoRows, err)
+		}
+	})
+}
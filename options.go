@@ -2,9 +2,33 @@ package postgres
 
 import "database/sql"
 
-type Option func(db *sql.DB, tablename string) error
+// Option configures a Store at construction time. Options are applied, in
+// order, after the base table has been created but before statements are
+// prepared against it, so an Option may alter the schema that New prepares
+// statements for.
+type Option func(s *Store, db *sql.DB, tablename string) error
 
-func WithCreateTable(db *sql.DB, tablename string) error {
+// WithCreateTable creates the table if it does not already exist.
+// New always ensures the table exists regardless of this option; it is kept
+// for callers that want that intent to be explicit.
+func WithCreateTable(s *Store, db *sql.DB, tablename string) error {
 	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS "` + tablename + `" (k TEXT NOT NULL PRIMARY KEY, v jsonb NOT NULL)`)
 	return err
 }
+
+// WithBatchSize sets the number of rows streamed per COPY round-trip by
+// BatchAdd and BatchSet. The default is defaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(s *Store, db *sql.DB, tablename string) error {
+		s.batchSize = n
+		return nil
+	}
+}
+
+// WithGINIndex creates a GIN index on the jsonb value column using the
+// jsonb_path_ops operator class, so that Filters built with Contains are
+// answered from the index rather than a sequential scan.
+func WithGINIndex(s *Store, db *sql.DB, tablename string) error {
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS "` + tablename + `_v_gin_idx" ON "` + tablename + `" USING GIN (v jsonb_path_ops)`)
+	return err
+}
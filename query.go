@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gokv/store"
+)
+
+// Filter narrows a GetAllWhere or Count query to rows whose jsonb value
+// matches a condition. Build one with Contains or FieldEquals; the zero
+// value matches every row.
+type Filter struct {
+	clause string
+	arg    interface{}
+}
+
+// Contains builds a Filter that matches rows whose value contains v, using
+// jsonb's "@>" containment operator.
+func Contains(v json.Marshaler) (Filter, error) {
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return Filter{}, err
+	}
+	return Filter{clause: "v @> %s::jsonb", arg: b}, nil
+}
+
+// fieldNameRE matches the jsonb field names FieldEquals accepts: field is
+// embedded directly in the query text (jsonb's -> operator takes a key, not
+// a bind parameter), so it is restricted to a safe identifier shape rather
+// than merely escaped.
+var fieldNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// FieldEquals builds a Filter that matches rows whose top-level field
+// equals the given JSON-encoded value, e.g. FieldEquals("status", `"done"`).
+// It returns an error if field is not a simple identifier.
+func FieldEquals(field string, jsonValue string) (Filter, error) {
+	if !fieldNameRE.MatchString(field) {
+		return Filter{}, fmt.Errorf("postgres: invalid field name %q", field)
+	}
+	return Filter{clause: `v -> '` + field + `' = %s::jsonb`, arg: jsonValue}, nil
+}
+
+// Page carries keyset pagination parameters for GetAllWhere: at most Limit
+// rows are returned, starting after AfterKey in key order. A zero Limit
+// means no limit, and an empty AfterKey starts from the beginning.
+type Page struct {
+	Limit    int
+	AfterKey string
+}
+
+// GetAllWhere appends to c every item matching filter, ordered by key and
+// restricted to page, instead of reading the whole table into memory like
+// GetAll does.
+// Err is non-nil in case of failure.
+func (s Store) GetAllWhere(ctx context.Context, filter Filter, page Page, c store.Collection) error {
+	query := `SELECT v FROM "` + s.tablename + `"`
+
+	var args []interface{}
+	var where []string
+
+	if filter.clause != "" {
+		args = append(args, filter.arg)
+		where = append(where, fmt.Sprintf(filter.clause, placeholder(len(args))))
+	}
+
+	if page.AfterKey != "" {
+		args = append(args, page.AfterKey)
+		where = append(where, fmt.Sprintf("k > %s", placeholder(len(args))))
+	}
+
+	if s.ttlEnabled {
+		where = append(where, `(expires_at IS NULL OR expires_at > now())`)
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	query += " ORDER BY k"
+
+	if page.Limit > 0 {
+		args = append(args, page.Limit)
+		query += fmt.Sprintf(" LIMIT %s", placeholder(len(args)))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var b []byte
+	for rows.Next() {
+		if err = rows.Scan(&b); err != nil {
+			return err
+		}
+		if err = c.New().UnmarshalJSON(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Count returns the number of rows matching filter.
+// Err is non-nil in case of failure.
+func (s Store) Count(ctx context.Context, filter Filter) (int64, error) {
+	query := `SELECT count(*) FROM "` + s.tablename + `"`
+
+	var args []interface{}
+	var where []string
+
+	if filter.clause != "" {
+		args = append(args, filter.arg)
+		where = append(where, fmt.Sprintf(filter.clause, placeholder(len(args))))
+	}
+
+	if s.ttlEnabled {
+		where = append(where, `(expires_at IS NULL OR expires_at > now())`)
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var n int64
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+func placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
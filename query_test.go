@@ -0,0 +1,130 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gokv/postgres"
+)
+
+func TestGetAllWhere(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_get_all_where", postgres.WithCreateTable, postgres.WithGINIndex)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_get_all_where"); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(
+		"INSERT INTO test_get_all_where (k, v) VALUES ($1, $2), ($3, $4), ($5, $6)",
+		"key0", `"value0"`, "key1", `"value1"`, "key2", `"value2"`,
+	); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	t.Run("applies a containment filter", func(t *testing.T) {
+		filter, err := postgres.Contains(String("value1"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var v StringCollection
+		if err := s.GetAllWhere(ctx, filter, postgres.Page{}, &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(v) != 1 || string(*v[0]) != "value1" {
+			t.Errorf("expected [value1], found %v", v)
+		}
+	})
+
+	t.Run("paginates by key", func(t *testing.T) {
+		var v StringCollection
+		page := postgres.Page{Limit: 1, AfterKey: "key0"}
+		if err := s.GetAllWhere(ctx, postgres.Filter{}, page, &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(v) != 1 || string(*v[0]) != "value1" {
+			t.Errorf("expected [value1], found %v", v)
+		}
+	})
+}
+
+func TestCount(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_count", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_count"); err != nil {
+		panic(err)
+	}
+
+	if _, err := db.Exec(
+		"INSERT INTO test_count (k, v) VALUES ($1, $2), ($3, $4)",
+		"key0", `"value0"`, "key1", `"value1"`,
+	); err != nil {
+		panic(err)
+	}
+
+	n, err := s.Count(context.Background(), postgres.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2, found %d", n)
+	}
+}
+
+func TestGetAllWhereAndCountHideExpiredRows(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_get_all_where_ttl", postgres.WithCreateTable, postgres.WithTTLColumn, postgres.WithSweepInterval(time.Hour))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_get_all_where_ttl"); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	if err := s.Set(ctx, "key0", String("value0")); err != nil {
+		panic(err)
+	}
+	if err := s.SetWithTTL(ctx, "key1", String("value1"), 50*time.Millisecond); err != nil {
+		panic(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	var v StringCollection
+	if err := s.GetAllWhere(ctx, postgres.Filter{}, postgres.Page{}, &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(v) != 1 || string(*v[0]) != "value0" {
+		t.Errorf("expected [value0], found %v", v)
+	}
+
+	n, err := s.Count(ctx, postgres.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1, found %d", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"time"
 
 	"github.com/gokv/store"
 	"github.com/google/uuid"
@@ -12,30 +13,64 @@ import (
 // Store holds the SQL statements prepared against a Postgresql table.
 // Initialise with New.
 type Store struct {
-	getStmt    *sql.Stmt
-	getAllStmt *sql.Stmt
-	addStmt    *sql.Stmt
-	setStmt    *sql.Stmt
-	updateStmt *sql.Stmt
-	deleteStmt *sql.Stmt
+	db        *sql.DB
+	tablename string
+	batchSize int
+	connInfo  string
+
+	ttlEnabled     bool
+	sweepInterval  time.Duration
+	sweepBatchSize int
+	stopSweep      chan struct{}
+	sweepDone      chan struct{}
+
+	getStmt        *sql.Stmt
+	getAllStmt     *sql.Stmt
+	addStmt        *sql.Stmt
+	setStmt        *sql.Stmt
+	updateStmt     *sql.Stmt
+	deleteStmt     *sql.Stmt
+	casStmt        *sql.Stmt
+	addWithTTLStmt *sql.Stmt
+	setWithTTLStmt *sql.Stmt
 
 	ping func(context.Context) error
 }
 
-// New creates a table of name tablename if it does not exist, and prepares
-// statements against it.
+// New creates a table of name tablename if it does not exist, applies opts,
+// and prepares statements against it.
 // The table has two columms: "k" is the TEXT primary key and "v" is a JSONb column holding the values.
-func New(db *sql.DB, tablename string) (s Store, err error) {
+func New(db *sql.DB, tablename string, opts ...Option) (s Store, err error) {
+	s.db = db
+	s.tablename = tablename
+	s.batchSize = defaultBatchSize
+
 	if _, err = db.Exec(`CREATE TABLE IF NOT EXISTS "` + tablename + `" (k TEXT NOT NULL PRIMARY KEY, v jsonb NOT NULL)`); err != nil {
 		return s, err
 	}
 
-	if s.getStmt, err = db.Prepare(`SELECT v FROM "` + tablename + `" WHERE k=$1`); err != nil {
+	for _, opt := range opts {
+		if err = opt(&s, db, tablename); err != nil {
+			_ = s.Close()
+			return s, err
+		}
+	}
+
+	notExpired := ""
+	if s.ttlEnabled {
+		notExpired = ` AND (expires_at IS NULL OR expires_at > now())`
+	}
+
+	if s.getStmt, err = db.Prepare(`SELECT v FROM "` + tablename + `" WHERE k=$1` + notExpired); err != nil {
 		_ = s.Close()
 		return s, err
 	}
 
-	if s.getAllStmt, err = db.Prepare(`SELECT v FROM "` + tablename + `"`); err != nil {
+	getAllWhere := ""
+	if s.ttlEnabled {
+		getAllWhere = ` WHERE expires_at IS NULL OR expires_at > now()`
+	}
+	if s.getAllStmt, err = db.Prepare(`SELECT v FROM "` + tablename + `"` + getAllWhere); err != nil {
 		_ = s.Close()
 		return s, err
 	}
@@ -45,29 +80,61 @@ func New(db *sql.DB, tablename string) (s Store, err error) {
 		return s, err
 	}
 
-	if s.setStmt, err = db.Prepare(`INSERT INTO "` + tablename + `" (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v=$2`); err != nil {
+	setOnConflict := `SET v=$2`
+	if s.ttlEnabled {
+		// Clear any expiry left over from a previous SetWithTTL; otherwise a
+		// plain Set over an expired row would write a fresh value that
+		// stays invisible until the old expires_at is overwritten.
+		setOnConflict = `SET v=$2, expires_at=NULL`
+	}
+	if s.setStmt, err = db.Prepare(`INSERT INTO "` + tablename + `" (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE ` + setOnConflict); err != nil {
+		_ = s.Close()
+		return s, err
+	}
+
+	if s.updateStmt, err = db.Prepare(`UPDATE "` + tablename + `" SET v=$2 WHERE k=$1` + notExpired); err != nil {
 		_ = s.Close()
 		return s, err
 	}
 
-	if s.updateStmt, err = db.Prepare(`UPDATE "` + tablename + `" SET v=$2 WHERE k=$1`); err != nil {
+	if s.deleteStmt, err = db.Prepare(`DELETE FROM "` + tablename + `" WHERE k=$1` + notExpired); err != nil {
 		_ = s.Close()
 		return s, err
 	}
 
-	if s.deleteStmt, err = db.Prepare(`DELETE FROM "` + tablename + `" WHERE k=$1`); err != nil {
+	if s.casStmt, err = db.Prepare(`UPDATE "` + tablename + `" SET v=$3 WHERE k=$1 AND v=$2::jsonb` + notExpired); err != nil {
 		_ = s.Close()
 		return s, err
 	}
 
+	if s.ttlEnabled {
+		if s.addWithTTLStmt, err = db.Prepare(`INSERT INTO "` + tablename + `" (k, v, expires_at) VALUES ($1, $2, now() + ($3 * interval '1 second'))`); err != nil {
+			_ = s.Close()
+			return s, err
+		}
+
+		if s.setWithTTLStmt, err = db.Prepare(`INSERT INTO "` + tablename + `" (k, v, expires_at) VALUES ($1, $2, now() + ($3 * interval '1 second')) ON CONFLICT (k) DO UPDATE SET v=$2, expires_at=now() + ($3 * interval '1 second')`); err != nil {
+			_ = s.Close()
+			return s, err
+		}
+
+		s.startSweeper()
+	}
+
 	s.ping = db.PingContext
 
 	return s, err
 }
 
-// Close releases the resources associated with the Store.
+// Close releases the resources associated with the Store, stopping the
+// background TTL sweeper, if any, and waiting for it to return.
 // Returns the first error encountered while closing the prepared statements.
 func (s Store) Close() (err error) {
+	if s.stopSweep != nil {
+		close(s.stopSweep)
+		<-s.sweepDone
+	}
+
 	for _, stmt := range []*sql.Stmt{
 		s.getStmt,
 		s.getAllStmt,
@@ -75,6 +142,9 @@ func (s Store) Close() (err error) {
 		s.setStmt,
 		s.updateStmt,
 		s.deleteStmt,
+		s.casStmt,
+		s.addWithTTLStmt,
+		s.setWithTTLStmt,
 	} {
 		if stmt != nil {
 			if e := stmt.Close(); err == nil {
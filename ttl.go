@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSweepInterval  = time.Minute
+	defaultSweepBatchSize = 1000
+)
+
+// ErrTTLNotConfigured is returned by SetWithTTL and AddWithTTL when the
+// Store was not constructed with WithTTLColumn.
+var ErrTTLNotConfigured = errors.New("postgres: store was not constructed with WithTTLColumn")
+
+// WithTTLColumn adds a nullable expires_at column to the table and enables
+// TTL-aware filtering on Get, GetAll, Update and Delete, so that expired
+// rows are invisible to reads and treated as not found by writes. It also
+// makes New start the background sweeper, which Close stops.
+func WithTTLColumn(s *Store, db *sql.DB, tablename string) error {
+	if _, err := db.Exec(`ALTER TABLE "` + tablename + `" ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL`); err != nil {
+		return err
+	}
+	s.ttlEnabled = true
+	return nil
+}
+
+// WithSweepInterval sets how often the background sweeper deletes expired
+// rows. It has no effect without WithTTLColumn. The default is
+// defaultSweepInterval.
+func WithSweepInterval(d time.Duration) Option {
+	return func(s *Store, db *sql.DB, tablename string) error {
+		s.sweepInterval = d
+		return nil
+	}
+}
+
+// WithSweepBatchSize caps the number of expired rows a single sweep deletes.
+// It has no effect without WithTTLColumn. The default is
+// defaultSweepBatchSize.
+func WithSweepBatchSize(n int) Option {
+	return func(s *Store, db *sql.DB, tablename string) error {
+		s.sweepBatchSize = n
+		return nil
+	}
+}
+
+// startSweeper launches the goroutine that periodically deletes expired
+// rows. It is only called by New when WithTTLColumn was applied.
+func (s *Store) startSweeper() {
+	interval := s.sweepInterval
+	if interval <= 0 {
+		interval = defaultSweepInterval
+	}
+
+	s.stopSweep = make(chan struct{})
+	s.sweepDone = make(chan struct{})
+
+	go func() {
+		defer close(s.sweepDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopSweep:
+				return
+			case <-ticker.C:
+				s.sweep()
+			}
+		}
+	}()
+}
+
+func (s *Store) sweep() {
+	batchSize := s.sweepBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultSweepBatchSize
+	}
+
+	_, _ = s.db.Exec(
+		`DELETE FROM "`+s.tablename+`" WHERE k IN (SELECT k FROM "`+s.tablename+`" WHERE expires_at <= now() LIMIT $1)`,
+		batchSize,
+	)
+}
+
+// SetWithTTL is like Set, but the row expires ttl after this call and
+// becomes invisible to reads once it does.
+// Err is non-nil in case of failure.
+func (s Store) SetWithTTL(ctx context.Context, k string, v json.Marshaler, ttl time.Duration) error {
+	if s.setWithTTLStmt == nil {
+		return ErrTTLNotConfigured
+	}
+
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.setWithTTLStmt.ExecContext(ctx, k, b, ttl.Seconds())
+	return err
+}
+
+// AddWithTTL is like Add, but the row expires ttl after this call and
+// becomes invisible to reads once it does.
+// Err is non-nil in case of failure.
+func (s Store) AddWithTTL(ctx context.Context, v json.Marshaler, ttl time.Duration) (string, error) {
+	if s.addWithTTLStmt == nil {
+		return "", ErrTTLNotConfigured
+	}
+
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+
+	k := uuid.New().String()
+
+	_, err = s.addWithTTLStmt.ExecContext(ctx, k, b, ttl.Seconds())
+	return k, err
+}
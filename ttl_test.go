@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gokv/postgres"
+	"github.com/gokv/store"
+)
+
+func TestSetWithTTL(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_ttl", postgres.WithCreateTable, postgres.WithTTLColumn, postgres.WithSweepInterval(50*time.Millisecond))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_ttl"); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	if err := s.SetWithTTL(ctx, "key", String("value"), 50*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v String
+	ok, err := s.Get(ctx, "key", &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected value to be found before expiry")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	ok, err = s.Get(ctx, "key", &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected expired value to be invisible")
+	}
+
+	if err := s.Update(ctx, "key", String("other value")); err != store.ErrNoRows {
+		t.Errorf("expected expired key to be treated as not found, found err=%v", err)
+	}
+}
+
+func TestSetClearsExpiry(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_ttl_set", postgres.WithCreateTable, postgres.WithTTLColumn, postgres.WithSweepInterval(time.Hour))
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_ttl_set"); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	if err := s.SetWithTTL(ctx, "key", String("value"), 50*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := s.Set(ctx, "key", String("fresh value")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v String
+	ok, err := s.Get(ctx, "key", &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected Set to clear the stale expiry and make the row visible again")
+	}
+	if have, want := v, String("fresh value"); have != want {
+		t.Errorf("expected %q, found %q", want, have)
+	}
+}
+
+func TestSetWithTTLRequiresTTLColumn(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_ttl_unconfigured", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+
+	if err := s.SetWithTTL(ctx, "key", String("value"), time.Second); err != postgres.ErrTTLNotConfigured {
+		t.Errorf("expected %v, found %v", postgres.ErrTTLNotConfigured, err)
+	}
+
+	if _, err := s.AddWithTTL(ctx, String("value"), time.Second); err != postgres.ErrTTLNotConfigured {
+		t.Errorf("expected %v, found %v", postgres.ErrTTLNotConfigured, err)
+	}
+}
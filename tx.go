@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gokv/store"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxInTxRetries bounds how many times, in all, InTx attempts a transaction
+// that keeps hitting serialization failures, so that contention can't stall
+// a caller forever.
+const maxInTxRetries = 10
+
+// Tx exposes the same Get/GetAll/Add/Set/Update/Delete surface as Store, but
+// routed through a single *sql.Tx so that several operations are committed
+// or rolled back together. Under Begin each statement sees its own READ
+// COMMITTED snapshot; under InTx they share one SERIALIZABLE snapshot.
+// Obtain one with Store.Begin or Store.InTx.
+type Tx struct {
+	tx *sql.Tx
+
+	getStmt    *sql.Stmt
+	getAllStmt *sql.Stmt
+	addStmt    *sql.Stmt
+	setStmt    *sql.Stmt
+	updateStmt *sql.Stmt
+	deleteStmt *sql.Stmt
+}
+
+// Begin starts a transaction at the default (READ COMMITTED) isolation
+// level and rebinds the Store's prepared statements to it with tx.Stmt, so
+// that a statement already prepared on the transaction's connection is
+// reused; database/sql prepares it on that connection otherwise.
+func (s Store) Begin(ctx context.Context) (*Tx, error) {
+	return s.beginTx(ctx, nil)
+}
+
+func (s Store) beginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	sqlTx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		tx:         sqlTx,
+		getStmt:    sqlTx.StmtContext(ctx, s.getStmt),
+		getAllStmt: sqlTx.StmtContext(ctx, s.getAllStmt),
+		addStmt:    sqlTx.StmtContext(ctx, s.addStmt),
+		setStmt:    sqlTx.StmtContext(ctx, s.setStmt),
+		updateStmt: sqlTx.StmtContext(ctx, s.updateStmt),
+		deleteStmt: sqlTx.StmtContext(ctx, s.deleteStmt),
+	}, nil
+}
+
+// InTx runs fn inside a SERIALIZABLE transaction, committing on success and
+// rolling back otherwise. If fn or Commit fails because of a serialization
+// failure (SQLSTATE 40001), the whole transaction is retried, for at most
+// maxInTxRetries attempts in all, so that the cross-key invariants fn enforces hold
+// even under concurrent InTx callers.
+func (s Store) InTx(ctx context.Context, fn func(*Tx) error) error {
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	var lastErr error
+	for attempt := 0; attempt < maxInTxRetries; attempt++ {
+		tx, err := s.beginTx(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(tx); err != nil {
+			_ = tx.Rollback()
+			if isSerializationFailure(err) {
+				lastErr = err
+				continue
+			}
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			if isSerializationFailure(err) {
+				lastErr = err
+				continue
+			}
+			return err
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("postgres: gave up after %d serialization failures, last error: %w", maxInTxRetries, lastErr)
+}
+
+func isSerializationFailure(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "40001"
+}
+
+// Commit commits the transaction.
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction.
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Get retrieves an item by key and unmarshals it into v, or returns false if
+// not found.
+// Err is non-nil in case of failure.
+func (t *Tx) Get(ctx context.Context, k string, v json.Unmarshaler) (bool, error) {
+	var b []byte
+	if err := t.getStmt.QueryRowContext(ctx, k).Scan(&b); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, v.UnmarshalJSON(b)
+}
+
+// GetAll appends to c every item in the store, as seen by this transaction.
+// Err is non-nil in case of failure.
+func (t *Tx) GetAll(ctx context.Context, c store.Collection) error {
+	rows, err := t.getAllStmt.QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var b []byte
+	for rows.Next() {
+		if err = rows.Scan(&b); err != nil {
+			return err
+		}
+		if err = c.New().UnmarshalJSON(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Add persists a new object and returns its unique UUIDv4 key.
+// Err is non-nil in case of failure.
+func (t *Tx) Add(ctx context.Context, v json.Marshaler) (string, error) {
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+
+	k := uuid.New().String()
+
+	_, err = t.addStmt.ExecContext(ctx, k, b)
+	return k, err
+}
+
+func (t *Tx) Set(ctx context.Context, k string, v json.Marshaler) error {
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	_, err = t.setStmt.ExecContext(ctx, k, b)
+	return err
+}
+
+// Update assigns the given value to the given key, if it exists.
+// Err is non-nil if the key was not already present, or in case of failure.
+func (t *Tx) Update(ctx context.Context, k string, v json.Marshaler) error {
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	res, err := t.updateStmt.ExecContext(ctx, k, b)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n < 1 {
+		return store.ErrNoRows
+	}
+
+	return nil
+}
+
+func (t *Tx) Delete(ctx context.Context, k string) error {
+	res, err := t.deleteStmt.ExecContext(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n < 1 {
+		return store.ErrNoRows
+	}
+
+	return nil
+}
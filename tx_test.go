@@ -0,0 +1,100 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gokv/postgres"
+)
+
+func TestTx(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_tx", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_tx"); err != nil {
+		panic(err)
+	}
+
+	t.Run("commits visible changes", func(t *testing.T) {
+		ctx := context.Background()
+
+		tx, err := s.Begin(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := tx.Set(ctx, "key", String("value")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var v String
+		ok, err := s.Get(ctx, "key", &v)
+		if err != nil || !ok {
+			t.Fatalf("expected value to be visible, ok=%v err=%v", ok, err)
+		}
+	})
+
+	t.Run("rollback discards changes", func(t *testing.T) {
+		ctx := context.Background()
+
+		tx, err := s.Begin(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := tx.Set(ctx, "key2", String("value2")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var v String
+		ok, err := s.Get(ctx, "key2", &v)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ok {
+			t.Error("expected rolled-back value to be absent")
+		}
+	})
+}
+
+func TestInTx(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+	s, err := postgres.New(db, "test_in_tx", postgres.WithCreateTable)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_in_tx"); err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	err = s.InTx(ctx, func(tx *postgres.Tx) error {
+		return tx.Set(ctx, "key", String("value"))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v String
+	ok, err := s.Get(ctx, "key", &v)
+	if err != nil || !ok {
+		t.Fatalf("expected value to be visible, ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Op identifies the kind of change that produced an Event. It mirrors the
+// trigger-level operation reported by PostgreSQL, which cannot distinguish a
+// Set that inserted a row from an Add, nor a Set that overwrote one from an
+// Update.
+type Op string
+
+const (
+	OpAdd    Op = "INSERT"
+	OpUpdate Op = "UPDATE"
+	OpDelete Op = "DELETE"
+)
+
+// Event describes a single create/update/delete on a watched key.
+type Event struct {
+	Op    Op
+	Key   string
+	Value json.RawMessage
+}
+
+// NewWithListener behaves like New, additionally recording the connection
+// string pq.NewListener needs to open the dedicated LISTEN/NOTIFY connection
+// that Watch uses.
+func NewWithListener(db *sql.DB, connInfo string, tablename string, opts ...Option) (s Store, err error) {
+	s, err = New(db, tablename, opts...)
+	s.connInfo = connInfo
+	return s, err
+}
+
+// notifyPayloadBudget is the largest whole payload, in bytes and including
+// 'op' and 'k', that the trigger sends with 'v'; it stays under PostgreSQL's
+// 8000-byte NOTIFY payload limit. A payload over it is sent without 'v'
+// rather than aborting the triggering write.
+const notifyPayloadBudget = 7800
+
+// WithChangeNotifications installs an AFTER INSERT OR UPDATE OR DELETE
+// trigger that calls pg_notify on channel "gokv_<tablename>" with the
+// affected key (and, for INSERT/UPDATE, value, when it fits the NOTIFY
+// payload size limit) so that Watch can observe changes.
+func WithChangeNotifications(s *Store, db *sql.DB, tablename string) error {
+	fn := `"gokv_notify_` + tablename + `"`
+	trigger := `"gokv_notify_` + tablename + `_trigger"`
+
+	if _, err := db.Exec(fmt.Sprintf(`
+CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+DECLARE
+	payload jsonb;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		payload := json_build_object('op', TG_OP, 'k', OLD.k);
+	ELSE
+		payload := json_build_object('op', TG_OP, 'k', NEW.k, 'v', NEW.v);
+		IF octet_length(payload::text) > %d THEN
+			payload := json_build_object('op', TG_OP, 'k', NEW.k);
+		END IF;
+	END IF;
+	PERFORM pg_notify('%s', payload::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+`, fn, notifyPayloadBudget, notifyChannel(tablename))); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DROP TRIGGER IF EXISTS ` + trigger + ` ON "` + tablename + `"`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+CREATE TRIGGER ` + trigger + `
+AFTER INSERT OR UPDATE OR DELETE ON "` + tablename + `"
+FOR EACH ROW EXECUTE PROCEDURE ` + fn + `();
+`)
+	return err
+}
+
+func notifyChannel(tablename string) string {
+	return "gokv_" + tablename
+}
+
+// Watch streams create/update/delete events for keys matching prefix. Value
+// is absent when the row it came from wouldn't fit the NOTIFY payload limit,
+// and always absent for deletes. The Store must have been built with
+// NewWithListener, and the table with WithChangeNotifications, for events to
+// be delivered. The returned channel is closed once ctx is cancelled; the
+// underlying listener connection reconnects on transient failures on its
+// own, logging each disconnect and each failed reconnect attempt.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	onEvent := func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("gokv/postgres: listener event %v on %q: %v", ev, s.tablename, err)
+		}
+	}
+
+	listener := pq.NewListener(s.connInfo, 10*time.Second, time.Minute, onEvent)
+	if err := listener.Listen(notifyChannel(s.tablename)); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer listener.Close()
+		defer close(events)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				if n == nil {
+					// pq.Listener sends a nil notification after it
+					// reconnects; the disconnect was already logged by
+					// onEvent. Changes made while it was disconnected
+					// produce no Event.
+					continue
+				}
+
+				var payload struct {
+					Op Op              `json:"op"`
+					K  string          `json:"k"`
+					V  json.RawMessage `json:"v"`
+				}
+				if err := json.Unmarshal([]byte(n.Extra), &payload); err != nil {
+					continue
+				}
+				if !strings.HasPrefix(payload.K, prefix) {
+					continue
+				}
+
+				select {
+				case events <- Event{Op: payload.Op, Key: payload.K, Value: payload.V}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gokv/postgres"
+)
+
+func connInfo() string {
+	var host string
+	if host = os.Getenv("POSTGRES_HOST"); host == "" {
+		host = "localhost"
+	}
+	return "host=" + host + " user=postgres dbname=store sslmode=disable"
+}
+
+func TestWatch(t *testing.T) {
+	db := newDB()
+	defer db.Close()
+
+	s, err := postgres.NewWithListener(db, connInfo(), "test_watch", postgres.WithCreateTable, postgres.WithChangeNotifications)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+
+	if _, err := db.Exec("DELETE FROM test_watch"); err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := s.Watch(ctx, "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// give pq.Listener time to establish the LISTEN before we notify.
+	time.Sleep(100 * time.Millisecond)
+
+	if err := s.Set(ctx, "key0", String("value0")); err != nil {
+		t.Fatalf("setting: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if have, want := ev.Key, "key0"; have != want {
+			t.Errorf("expected key %q, found %q", want, have)
+		}
+		if ev.Op != postgres.OpAdd {
+			t.Errorf("expected op %q, found %q", postgres.OpAdd, ev.Op)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}